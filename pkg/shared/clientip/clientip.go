@@ -0,0 +1,93 @@
+// Package clientip resolves the real client IP behind trusted reverse
+// proxies: it walks X-Forwarded-For right-to-left skipping trusted hops,
+// prefers X-Real-IP when the immediate peer is trusted, and otherwise falls
+// back to the raw TCP peer address.
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ParseTrustedProxies parses a comma-separated list of CIDRs (as read from
+// the TRUSTED_PROXIES env var). A bare IP is treated as a /32 (or /128 for
+// IPv6).
+func ParseTrustedProxies(raw string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		if !strings.Contains(part, "/") {
+			ip := net.ParseIP(part)
+			if ip == nil {
+				return nil, fmt.Errorf("clientip: invalid trusted proxy %q", part)
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			part = fmt.Sprintf("%s/%d", part, bits)
+		}
+
+		_, n, err := net.ParseCIDR(part)
+		if err != nil {
+			return nil, fmt.Errorf("clientip: invalid trusted proxy CIDR %q: %w", part, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// Resolve returns the best-guess real client IP for r. trusted is the set
+// of CIDRs allowed to set X-Forwarded-For/X-Real-IP; headers from any other
+// peer are ignored since they could be spoofed.
+func Resolve(r *http.Request, trusted []*net.IPNet) string {
+	peerHost := hostOnly(r.RemoteAddr)
+
+	peerIP := net.ParseIP(peerHost)
+	if peerIP == nil || !isTrusted(peerIP, trusted) {
+		return peerHost
+	}
+
+	if real := strings.TrimSpace(r.Header.Get("X-Real-IP")); real != "" {
+		return real
+	}
+
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			ip := net.ParseIP(hop)
+			if ip == nil {
+				continue // malformed entry — skip it, keep walking left
+			}
+			if !isTrusted(ip, trusted) {
+				return hop
+			}
+		}
+	}
+
+	return peerHost
+}
+
+func hostOnly(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+func isTrusted(ip net.IP, trusted []*net.IPNet) bool {
+	for _, n := range trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
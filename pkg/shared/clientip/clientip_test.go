@@ -0,0 +1,87 @@
+package clientip
+
+import (
+	"net"
+	"net/http"
+	"testing"
+)
+
+func trustedProxies(t *testing.T, raw string) []*net.IPNet {
+	t.Helper()
+	nets, err := ParseTrustedProxies(raw)
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies(%q): %v", raw, err)
+	}
+	return nets
+}
+
+func TestResolveIPv6WithPort(t *testing.T) {
+	r := &http.Request{Header: http.Header{}, RemoteAddr: "[2001:db8::1]:54321"}
+
+	got := Resolve(r, nil)
+	want := "2001:db8::1"
+	if got != want {
+		t.Errorf("Resolve() = %q, want %q", got, want)
+	}
+}
+
+func TestResolveMultipleXFFHops(t *testing.T) {
+	trusted := trustedProxies(t, "10.0.0.0/8")
+
+	r := &http.Request{
+		Header:     http.Header{"X-Forwarded-For": []string{"203.0.113.5, 10.0.0.2, 10.0.0.1"}},
+		RemoteAddr: "10.0.0.1:443",
+	}
+
+	got := Resolve(r, trusted)
+	want := "203.0.113.5"
+	if got != want {
+		t.Errorf("Resolve() = %q, want %q", got, want)
+	}
+}
+
+func TestResolveSpoofedXRealIPFromUntrustedPeer(t *testing.T) {
+	trusted := trustedProxies(t, "10.0.0.0/8")
+
+	r := &http.Request{
+		Header:     http.Header{"X-Real-Ip": []string{"1.2.3.4"}},
+		RemoteAddr: "203.0.113.9:1234",
+	}
+
+	got := Resolve(r, trusted)
+	want := "203.0.113.9"
+	if got != want {
+		t.Errorf("Resolve() = %q, want %q (spoofed X-Real-IP from an untrusted peer must be ignored)", got, want)
+	}
+}
+
+func TestResolveMalformedHeaders(t *testing.T) {
+	trusted := trustedProxies(t, "10.0.0.0/8")
+
+	r := &http.Request{
+		Header:     http.Header{"X-Forwarded-For": []string{"not-an-ip, 10.0.0.1"}},
+		RemoteAddr: "10.0.0.1:443",
+	}
+
+	got := Resolve(r, trusted)
+	want := "10.0.0.1"
+	if got != want {
+		t.Errorf("Resolve() = %q, want %q (malformed hop should be skipped, falling back to peer)", got, want)
+	}
+}
+
+func TestParseTrustedProxiesBareIP(t *testing.T) {
+	nets, err := ParseTrustedProxies("10.0.0.1, 2001:db8::1")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies: %v", err)
+	}
+	if len(nets) != 2 {
+		t.Fatalf("got %d nets, want 2", len(nets))
+	}
+}
+
+func TestParseTrustedProxiesInvalid(t *testing.T) {
+	if _, err := ParseTrustedProxies("not-a-cidr"); err == nil {
+		t.Fatal("expected an error for an invalid trusted proxy entry")
+	}
+}
@@ -0,0 +1,30 @@
+package clientip
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+)
+
+type contextKey struct{}
+
+// FromContext returns the client IP resolved by LoggingMiddleware for this
+// request, if any.
+func FromContext(ctx context.Context) (string, bool) {
+	ip, ok := ctx.Value(contextKey{}).(string)
+	return ip, ok
+}
+
+// LoggingMiddleware resolves the real client IP for every request (logging
+// it alongside the method and path) and stashes it in the request context
+// for downstream handlers to read via FromContext.
+func LoggingMiddleware(service string, trusted []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := Resolve(r, trusted)
+			log.Printf("%s: %s %s — client %s", service, r.Method, r.URL.Path, ip)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, ip)))
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package events
+
+import "sync"
+
+// subscriber is a single subscriber's non-blocking mailbox: a fixed-size
+// ring buffer that drops the oldest event once a slow consumer falls
+// behind, so one stuck subscriber can't back up the broker.
+type subscriber struct {
+	filter Filter
+	ch     chan Event
+}
+
+// Broker fans events out to any number of subscribers. Publish never
+// blocks on a slow consumer — it drops that subscriber's oldest buffered
+// event to make room instead.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[*subscriber]struct{}
+	bufferSize  int
+}
+
+// NewBroker returns a Broker whose subscribers each buffer up to
+// bufferSize events before dropping the oldest.
+func NewBroker(bufferSize int) *Broker {
+	return &Broker{subscribers: make(map[*subscriber]struct{}), bufferSize: bufferSize}
+}
+
+// Publish fans e out to every subscriber whose filter matches it.
+func (b *Broker) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers {
+		if !sub.filter.matches(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			select {
+			case <-sub.ch:
+			default:
+			}
+			select {
+			case sub.ch <- e:
+			default:
+			}
+		}
+	}
+}
+
+func (b *Broker) subscribe(filter Filter) (*subscriber, func()) {
+	sub := &subscriber{filter: filter, ch: make(chan Event, b.bufferSize)}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub, func() {
+		b.mu.Lock()
+		delete(b.subscribers, sub)
+		b.mu.Unlock()
+		close(sub.ch)
+	}
+}
@@ -0,0 +1,56 @@
+// Package events implements a fan-out broker for structured event streams,
+// served over a minimal RFC 6455 WebSocket implementation (no external
+// dependency — this repo has none), giving operators a live tail equivalent
+// to `kubectl logs -f` without shelling into containers.
+package events
+
+import (
+	"strings"
+	"time"
+)
+
+// Event is a single structured line published onto a Broker: a request
+// served, a healthcheck toggle, a job delivery outcome, a signal received,
+// a probe result, and so on.
+type Event struct {
+	Service   string    `json:"service"`
+	Kind      string    `json:"kind"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Filter restricts a subscription to events matching specific field=value
+// pairs, as parsed from a ?filter=service=worker,kind=job_failed query
+// param. Unrecognized fields are ignored.
+type Filter map[string]string
+
+// ParseFilter parses a comma-separated list of field=value pairs.
+func ParseFilter(raw string) Filter {
+	if raw == "" {
+		return nil
+	}
+	f := make(Filter)
+	for _, pair := range strings.Split(raw, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && k != "" {
+			f[k] = v
+		}
+	}
+	return f
+}
+
+func (f Filter) matches(e Event) bool {
+	for k, v := range f {
+		switch k {
+		case "service":
+			if e.Service != v {
+				return false
+			}
+		case "kind":
+			if e.Kind != v {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package events
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// heartbeatInterval is how often a ping frame is sent to a subscriber to
+// detect dead connections before the OS notices for us.
+const heartbeatInterval = 15 * time.Second
+
+// Handler returns a GET /events handler that upgrades the connection to a
+// WebSocket and streams matching events as JSON text frames until the
+// client disconnects. An optional ?filter=service=worker,kind=job_failed
+// query param restricts the stream, e.g. to a single service or event kind.
+func (b *Broker) Handler(service string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgradeWebSocket(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer conn.close()
+
+		filter := ParseFilter(r.URL.Query().Get("filter"))
+		sub, unsubscribe := b.subscribe(filter)
+		defer unsubscribe()
+
+		// readDone is closed once the background reader sees the client go
+		// away (Close frame, reset, or a missed heartbeat deadline) — it's
+		// our only signal that this connection is no longer worth writing to.
+		readDone := make(chan struct{})
+		go func() {
+			defer close(readDone)
+			for {
+				conn.setReadDeadline(time.Now().Add(2 * heartbeatInterval))
+				op, _, err := conn.readFrame()
+				if err != nil {
+					return
+				}
+				if op == opClose {
+					return
+				}
+			}
+		}()
+
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-readDone:
+				return
+			case <-ticker.C:
+				if err := conn.writePing(); err != nil {
+					return
+				}
+			case e, ok := <-sub.ch:
+				if !ok {
+					return
+				}
+				payload, err := json.Marshal(e)
+				if err != nil {
+					log.Printf("%s: events: marshal: %v", service, err)
+					continue
+				}
+				if err := conn.writeText(payload); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
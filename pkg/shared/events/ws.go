@@ -0,0 +1,176 @@
+package events
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// wsGUID is the magic value RFC 6455 section 1.3 defines for computing
+// Sec-WebSocket-Accept from the client's Sec-WebSocket-Key.
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxFrameSize bounds how large a client frame readFrame will allocate for.
+// /events only expects tiny control/Close frames back from clients, so this
+// is generous; it exists to stop a malicious length field from forcing a
+// multi-GB allocation.
+const maxFrameSize = 64 * 1024
+
+// errFrameTooLarge is returned by readFrame when a client claims a payload
+// length over maxFrameSize.
+var errFrameTooLarge = errors.New("events: frame exceeds max size")
+
+type opcode byte
+
+const (
+	opContinuation opcode = 0x0
+	opText         opcode = 0x1
+	opClose        opcode = 0x8
+	opPing         opcode = 0x9
+	opPong         opcode = 0xA
+)
+
+// wsConn is a hijacked HTTP connection speaking just enough of the RFC 6455
+// WebSocket frame format to push JSON text frames to a subscriber and
+// notice when it goes away — no external dependency needed for that.
+type wsConn struct {
+	rw *bufio.ReadWriter
+	nc net.Conn
+}
+
+// upgradeWebSocket performs the WebSocket handshake (RFC 6455 section 4.2)
+// and hands back the hijacked connection for framing.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") ||
+		!strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade") {
+		return nil, errors.New("events: not a websocket upgrade request")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, errors.New("events: missing Sec-WebSocket-Key")
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("events: response writer does not support hijacking")
+	}
+	nc, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("events: hijack: %w", err)
+	}
+
+	sum := sha1.Sum([]byte(key + wsGUID))
+	accept := base64.StdEncoding.EncodeToString(sum[:])
+
+	if _, err := rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + accept + "\r\n\r\n"); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	if err := rw.Flush(); err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	return &wsConn{rw: rw, nc: nc}, nil
+}
+
+// writeFrame writes a single, unfragmented server-to-client frame. Server
+// frames are sent unmasked, per spec.
+func (c *wsConn) writeFrame(op opcode, payload []byte) error {
+	header := make([]byte, 0, 10)
+	header = append(header, 0x80|byte(op))
+
+	n := len(payload)
+	switch {
+	case n <= 125:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126)
+		var ext [2]byte
+		binary.BigEndian.PutUint16(ext[:], uint16(n))
+		header = append(header, ext[:]...)
+	default:
+		header = append(header, 127)
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(n))
+		header = append(header, ext[:]...)
+	}
+
+	if _, err := c.rw.Write(header); err != nil {
+		return err
+	}
+	if _, err := c.rw.Write(payload); err != nil {
+		return err
+	}
+	return c.rw.Flush()
+}
+
+func (c *wsConn) writeText(payload []byte) error { return c.writeFrame(opText, payload) }
+func (c *wsConn) writePing() error               { return c.writeFrame(opPing, nil) }
+
+// readFrame reads a single client frame and unmasks its payload (client
+// frames are always masked per spec). Callers only need this to notice a
+// Close frame or a dead connection — anything else can be discarded.
+func (c *wsConn) readFrame() (opcode, []byte, error) {
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(c.rw, head); err != nil {
+		return 0, nil, err
+	}
+
+	op := opcode(head[0] & 0x0F)
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(c.rw, ext); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(c.rw, ext); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+
+	if length > maxFrameSize {
+		return 0, nil, errFrameTooLarge
+	}
+
+	var maskKey [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.rw, maskKey[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.rw, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= maskKey[i%4]
+		}
+	}
+
+	return op, payload, nil
+}
+
+func (c *wsConn) setReadDeadline(d time.Time) error { return c.nc.SetReadDeadline(d) }
+
+func (c *wsConn) close() error { return c.nc.Close() }
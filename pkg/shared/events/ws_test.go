@@ -0,0 +1,45 @@
+package events
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestReadFrameRejectsOversizedLength ensures a client-claimed frame length
+// well beyond maxFrameSize is rejected before readFrame allocates a buffer
+// for it, instead of trusting the length and blocking on (or allocating
+// for) a payload that's never actually sent.
+func TestReadFrameRejectsOversizedLength(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	conn := &wsConn{rw: bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), nc: server}
+
+	go func() {
+		header := []byte{0x80 | byte(opText), 0x80 | 127}
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], 1<<34) // ~16GiB, far over maxFrameSize
+		client.Write(header)
+		client.Write(ext[:])
+		// Deliberately never send the mask key or payload.
+	}()
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, _, err := conn.readFrame()
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != errFrameTooLarge {
+			t.Fatalf("readFrame() error = %v, want %v", err, errFrameTooLarge)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("readFrame did not return promptly for an oversized length claim")
+	}
+}
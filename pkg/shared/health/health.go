@@ -0,0 +1,131 @@
+// Package health provides a readiness-check registry for the Kubernetes
+// readyz/livez convention: liveness only tracks whether the process itself
+// should keep running, while readiness is the logical AND of every
+// registered dependency check.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/unkeyed/mono-repo-test/pkg/shared"
+)
+
+// Check reports whether a single dependency is ready to serve traffic. A
+// non-nil error means the check failed and readiness should be withheld.
+type Check func(ctx context.Context) error
+
+// Registry tracks named readiness checks and serves them as a /readyz
+// handler. The zero value is not usable — construct with NewRegistry.
+type Registry struct {
+	mu     sync.RWMutex
+	checks map[string]Check
+	order  []string
+}
+
+// NewRegistry returns an empty Registry ready for Register calls.
+func NewRegistry() *Registry {
+	return &Registry{checks: make(map[string]Check)}
+}
+
+// Register adds or replaces a named check. Checks run concurrently when
+// /readyz is hit, so they should be cheap and safe for concurrent use.
+func (r *Registry) Register(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.checks[name]; !exists {
+		r.order = append(r.order, name)
+	}
+	r.checks[name] = check
+}
+
+type checkResult struct {
+	name string
+	err  error
+}
+
+func (r *Registry) run(ctx context.Context, exclude map[string]bool) []checkResult {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.order))
+	for _, name := range r.order {
+		if !exclude[name] {
+			names = append(names, name)
+		}
+	}
+	checks := make(map[string]Check, len(names))
+	for _, name := range names {
+		checks[name] = r.checks[name]
+	}
+	r.mu.RUnlock()
+
+	results := make([]checkResult, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			results[i] = checkResult{name: name, err: checks[name](ctx)}
+		}(i, name)
+	}
+	wg.Wait()
+	return results
+}
+
+// ReadyzHandler returns an http.HandlerFunc for GET /readyz. It responds 200
+// when every registered check (minus any named in ?exclude=a,b) passes, and
+// 503 otherwise. ?verbose=1 includes a per-check breakdown in the message.
+func (r *Registry) ReadyzHandler(service, port string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		exclude := make(map[string]bool)
+		if raw := req.URL.Query().Get("exclude"); raw != "" {
+			for _, name := range strings.Split(raw, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					exclude[name] = true
+				}
+			}
+		}
+
+		results := r.run(req.Context(), exclude)
+
+		var failed []string
+		for _, res := range results {
+			if res.err != nil {
+				failed = append(failed, res.name)
+			}
+		}
+
+		resp := shared.Response{Service: service, Port: port}
+		status := http.StatusOK
+		if len(failed) > 0 {
+			status = http.StatusServiceUnavailable
+			resp.Status = "not_ready"
+		} else {
+			resp.Status = "ready"
+		}
+
+		switch {
+		case req.URL.Query().Get("verbose") == "1":
+			resp.Message = verboseBreakdown(results)
+		case len(failed) > 0:
+			resp.Message = fmt.Sprintf("failing checks: %s", strings.Join(failed, ", "))
+		}
+
+		shared.JSON(w, req, status, resp)
+	}
+}
+
+func verboseBreakdown(results []checkResult) string {
+	parts := make([]string, len(results))
+	for i, res := range results {
+		if res.err != nil {
+			parts[i] = fmt.Sprintf("[-] %s failed: %v", res.name, res.err)
+		} else {
+			parts[i] = fmt.Sprintf("[+] %s ok", res.name)
+		}
+	}
+	return strings.Join(parts, " / ")
+}
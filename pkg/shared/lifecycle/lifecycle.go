@@ -0,0 +1,121 @@
+// Package lifecycle drives an http.Server through a real graceful shutdown:
+// stop accepting new connections, let in-flight ones drain, run any
+// before/after hooks, and only force-close once a grace period elapses.
+package lifecycle
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Phase marks which side of the shutdown a Hook runs on.
+type Phase int
+
+const (
+	// PreShutdown hooks run before srv.Shutdown is called — e.g. flushing
+	// pending work while the server can still serve in-flight requests.
+	PreShutdown Phase = iota
+	// PostShutdown hooks run after the server has stopped serving, whether
+	// it drained cleanly or was force-closed.
+	PostShutdown
+)
+
+// Hook is a named shutdown action run at a given Phase.
+type Hook struct {
+	Phase Phase
+	Name  string
+	Fn    func(ctx context.Context)
+}
+
+// Pre builds a PreShutdown hook.
+func Pre(name string, fn func(ctx context.Context)) Hook {
+	return Hook{Phase: PreShutdown, Name: name, Fn: fn}
+}
+
+// Post builds a PostShutdown hook.
+func Post(name string, fn func(ctx context.Context)) Hook {
+	return Hook{Phase: PostShutdown, Name: name, Fn: fn}
+}
+
+// Run starts srv and blocks until it stops: either it returns an error on
+// its own, or a SIGTERM/SIGINT/SIGQUIT arrives and a graceful shutdown
+// completes. grace bounds how long Shutdown waits for in-flight connections
+// to drain before Run falls back to srv.Close(). SIGKILL is deliberately
+// not handled — the OS can't deliver it to a running process for us to
+// catch.
+func Run(ctx context.Context, srv *http.Server, grace time.Duration, hooks ...Hook) error {
+	var active atomic.Int64
+	if srv.ConnState == nil {
+		srv.ConnState = func(conn net.Conn, state http.ConnState) {
+			switch state {
+			case http.StateNew:
+				active.Add(1)
+			case http.StateClosed, http.StateHijacked:
+				active.Add(-1)
+			}
+		}
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		if err == http.ErrServerClosed {
+			return nil
+		}
+		return err
+	case s := <-sig:
+		log.Printf("lifecycle: received %s — starting graceful shutdown", s)
+		runHooks(ctx, hooks, PreShutdown)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+		defer cancel()
+
+		done := make(chan error, 1)
+		go func() { done <- srv.Shutdown(shutdownCtx) }()
+
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+	drain:
+		for {
+			select {
+			case err := <-done:
+				if err != nil {
+					log.Printf("lifecycle: graceful shutdown deadline exceeded (%d still in-flight), forcing close: %v", active.Load(), err)
+					srv.Close()
+				} else {
+					log.Println("lifecycle: clean shutdown, all connections drained")
+				}
+				break drain
+			case <-ticker.C:
+				log.Printf("lifecycle: draining %d in-flight connections", active.Load())
+			}
+		}
+
+		runHooks(ctx, hooks, PostShutdown)
+		return nil
+	}
+}
+
+func runHooks(ctx context.Context, hooks []Hook, phase Phase) {
+	for _, h := range hooks {
+		if h.Phase != phase {
+			continue
+		}
+		log.Printf("lifecycle: running hook %q", h.Name)
+		h.Fn(ctx)
+	}
+}
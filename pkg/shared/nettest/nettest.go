@@ -0,0 +1,213 @@
+// Package nettest implements a continuous all-pairs connectivity mesh,
+// modeled on Kubernetes' nettest pod: every instance periodically writes to
+// every configured peer and tracks which peers it has heard back from, so a
+// CI job can assert the mesh converges under a given network policy.
+package nettest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoadPeers reads peer URLs from the PEERS env var (comma-separated) or, if
+// unset, from the newline-separated file at PEERS_FILE. Returns an empty
+// slice if neither is set.
+func LoadPeers() ([]string, error) {
+	if raw := os.Getenv("PEERS"); raw != "" {
+		return splitNonEmpty(raw, ","), nil
+	}
+
+	path := os.Getenv("PEERS_FILE")
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("nettest: reading PEERS_FILE %s: %w", path, err)
+	}
+	return splitNonEmpty(string(data), "\n"), nil
+}
+
+func splitNonEmpty(raw, sep string) []string {
+	var out []string
+	for _, part := range strings.Split(raw, sep) {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+type writeRequest struct {
+	From string `json:"from"`
+}
+
+// Mesh probes a fixed set of peers on a schedule and tracks which of them
+// have written back, exposing the result as pass/running/fail for CI gating
+// of network-policy changes.
+type Mesh struct {
+	self     string
+	peers    []string
+	peerSet  map[string]struct{}
+	interval time.Duration
+	timeout  time.Duration
+	client   *http.Client
+
+	mu      sync.RWMutex
+	heard   map[string][]time.Time
+	started time.Time
+}
+
+// NewMesh builds a Mesh that identifies itself as self when writing to
+// peers. interval controls how often it writes to every peer; timeout is
+// how long the mesh has to fully converge before Status reports "fail".
+func NewMesh(self string, peers []string, interval, timeout time.Duration) *Mesh {
+	peerSet := make(map[string]struct{}, len(peers))
+	for _, peer := range peers {
+		peerSet[peer] = struct{}{}
+	}
+	return &Mesh{
+		self:     self,
+		peers:    peers,
+		peerSet:  peerSet,
+		interval: interval,
+		timeout:  timeout,
+		client:   &http.Client{Timeout: interval / 2},
+		heard:    make(map[string][]time.Time),
+	}
+}
+
+// Run starts the periodic write loop and blocks until ctx is canceled.
+// Callers should invoke it in its own goroutine.
+func (m *Mesh) Run(ctx context.Context) {
+	m.mu.Lock()
+	m.started = time.Now()
+	m.mu.Unlock()
+
+	tick := time.NewTicker(m.interval)
+	defer tick.Stop()
+
+	m.writeAll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			m.writeAll(ctx)
+		}
+	}
+}
+
+func (m *Mesh) writeAll(ctx context.Context) {
+	for _, peer := range m.peers {
+		peer := peer
+		go func() {
+			body, _ := json.Marshal(writeRequest{From: m.self})
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, peer+"/nettest/write", bytes.NewReader(body))
+			if err != nil {
+				return
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := m.client.Do(req)
+			if err != nil {
+				log.Printf("nettest: write to %s failed: %v", peer, err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+}
+
+// maxWriteBodyBytes bounds a POST /nettest/write request body, so it can't
+// be used to exhaust memory independently of the "from" check below.
+const maxWriteBodyBytes = 4 << 10 // 4KiB
+
+// WriteHandler serves POST /nettest/write, recording the timestamp a peer
+// wrote to us. Only senders in our own configured peer list are recorded —
+// otherwise an attacker-controlled "from" value could grow m.heard without
+// bound.
+func (m *Mesh) WriteHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxWriteBodyBytes)
+
+		var req writeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.From == "" {
+			http.Error(w, "missing or invalid \"from\"", http.StatusBadRequest)
+			return
+		}
+		if _, ok := m.peerSet[req.From]; !ok {
+			http.Error(w, "unknown \"from\" peer", http.StatusBadRequest)
+			return
+		}
+
+		m.mu.Lock()
+		m.heard[req.From] = append(m.heard[req.From], time.Now())
+		m.mu.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ReadHandler serves GET /nettest/read, returning a JSON map of peer to the
+// timestamps we've heard from it.
+func (m *Mesh) ReadHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.mu.RLock()
+		snapshot := make(map[string][]time.Time, len(m.heard))
+		for peer, seen := range m.heard {
+			snapshot[peer] = append([]time.Time(nil), seen...)
+		}
+		m.mu.RUnlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(snapshot)
+	}
+}
+
+type statusResponse struct {
+	Status  string   `json:"status"`
+	Heard   []string `json:"heard"`
+	Missing []string `json:"missing,omitempty"`
+}
+
+// StatusHandler serves GET /nettest/status, reporting "pass" once every
+// configured peer has been heard from, "running" while still within the
+// convergence timeout, and "fail" once that timeout has elapsed.
+func (m *Mesh) StatusHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.mu.RLock()
+		var heard, missing []string
+		for _, peer := range m.peers {
+			if len(m.heard[peer]) > 0 {
+				heard = append(heard, peer)
+			} else {
+				missing = append(missing, peer)
+			}
+		}
+		elapsed := time.Since(m.started)
+		m.mu.RUnlock()
+
+		resp := statusResponse{Heard: heard, Missing: missing}
+		switch {
+		case len(missing) == 0:
+			resp.Status = "pass"
+		case elapsed < m.timeout:
+			resp.Status = "running"
+		default:
+			resp.Status = "fail"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
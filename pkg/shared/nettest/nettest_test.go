@@ -0,0 +1,86 @@
+package nettest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestTwoInstanceConvergence spins up two meshes, each identifying itself
+// using the same URL form the other's PEERS list uses to dial it, and
+// asserts status eventually reports "pass" for both once they've exchanged
+// writes.
+func TestTwoInstanceConvergence(t *testing.T) {
+	muxA := http.NewServeMux()
+	muxB := http.NewServeMux()
+	srvA := httptest.NewServer(muxA)
+	srvB := httptest.NewServer(muxB)
+	defer srvA.Close()
+	defer srvB.Close()
+
+	meshA := NewMesh(srvA.URL, []string{srvB.URL}, 10*time.Millisecond, time.Second)
+	meshB := NewMesh(srvB.URL, []string{srvA.URL}, 10*time.Millisecond, time.Second)
+
+	muxA.HandleFunc("/nettest/write", meshA.WriteHandler())
+	muxA.HandleFunc("/nettest/status", meshA.StatusHandler())
+	muxB.HandleFunc("/nettest/write", meshB.WriteHandler())
+	muxB.HandleFunc("/nettest/status", meshB.StatusHandler())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go meshA.Run(ctx)
+	go meshB.Run(ctx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		a := statusOf(t, meshA)
+		b := statusOf(t, meshB)
+		if a.Status == "pass" && b.Status == "pass" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("mesh did not converge to pass within the timeout: A=%+v B=%+v", statusOf(t, meshA), statusOf(t, meshB))
+}
+
+// TestWriteHandlerRejectsUnknownPeer ensures a "from" value outside the
+// mesh's configured peer list is rejected rather than recorded, so an
+// arbitrary sender can't grow m.heard without bound.
+func TestWriteHandlerRejectsUnknownPeer(t *testing.T) {
+	mesh := NewMesh("http://self", []string{"http://known-peer"}, time.Second, time.Second)
+
+	body, err := json.Marshal(writeRequest{From: "http://unknown-peer"})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/nettest/write", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	mesh.WriteHandler()(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d for an unknown peer", rr.Code, http.StatusBadRequest)
+	}
+	if len(mesh.heard["http://unknown-peer"]) != 0 {
+		t.Error("WriteHandler recorded a write from a peer outside the configured peer list")
+	}
+}
+
+func statusOf(t *testing.T, m *Mesh) statusResponse {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/nettest/status", nil)
+	m.StatusHandler()(rr, req)
+
+	var resp statusResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding status response: %v", err)
+	}
+	return resp
+}
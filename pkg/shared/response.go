@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"time"
+
+	"github.com/unkeyed/mono-repo-test/pkg/shared/clientip"
 )
 
 type Response struct {
@@ -12,10 +14,19 @@ type Response struct {
 	Port      string `json:"port"`
 	Timestamp string `json:"timestamp"`
 	Message   string `json:"message,omitempty"`
+	ClientIP  string `json:"client_ip,omitempty"`
 }
 
-func JSON(w http.ResponseWriter, status int, resp Response) {
+// JSON writes resp as the response body. When r carries ?debug=1 and a
+// client IP was resolved for it (see clientip.LoggingMiddleware), resp's
+// ClientIP field is populated before encoding.
+func JSON(w http.ResponseWriter, r *http.Request, status int, resp Response) {
 	resp.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	if r.URL.Query().Get("debug") == "1" {
+		if ip, ok := clientip.FromContext(r.Context()); ok {
+			resp.ClientIP = ip
+		}
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(resp)
@@ -0,0 +1,51 @@
+package queue
+
+import (
+	"net/url"
+	"sync"
+	"time"
+)
+
+// breaker tracks consecutive delivery failures against a single host and
+// opens for a cooldown window once a threshold is crossed, pausing delivery
+// to that host without failing the jobs outright.
+type breaker struct {
+	mu               sync.Mutex
+	consecutiveFails int
+	openUntil        time.Time
+}
+
+func (b *breaker) open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Now().Before(b.openUntil)
+}
+
+func (b *breaker) recordFailure(threshold int, cooldown time.Duration) (tripped bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.consecutiveFails++
+	if b.consecutiveFails >= threshold {
+		b.openUntil = time.Now().Add(cooldown)
+		return true
+	}
+	return false
+}
+
+func (b *breaker) recordSuccess() {
+	b.mu.Lock()
+	b.consecutiveFails = 0
+	b.openUntil = time.Time{}
+	b.mu.Unlock()
+}
+
+// hostOf returns the host component of a target URL, or the raw string if
+// it doesn't parse as a URL.
+func hostOf(target string) string {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return target
+	}
+	return u.Host
+}
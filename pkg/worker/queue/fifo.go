@@ -0,0 +1,91 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrFull is returned by Enqueue when the FIFO is at capacity.
+var ErrFull = errors.New("queue: at capacity")
+
+// FIFO is a bounded in-memory first-in-first-out job queue. It supports
+// removing queued-but-not-yet-dequeued jobs by host, which backs the
+// DELETE /jobs?target=host purge endpoint.
+type FIFO struct {
+	mu       sync.Mutex
+	items    []*Job
+	capacity int
+	tokens   chan struct{}
+}
+
+// NewFIFO returns an empty FIFO that rejects Enqueue once it holds capacity
+// items.
+func NewFIFO(capacity int) *FIFO {
+	return &FIFO{capacity: capacity, tokens: make(chan struct{}, capacity)}
+}
+
+// Enqueue appends job to the tail of the queue, or returns ErrFull if the
+// queue is already at capacity.
+func (f *FIFO) Enqueue(job *Job) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.items) >= f.capacity {
+		return ErrFull
+	}
+	f.items = append(f.items, job)
+
+	// Sent while still holding mu so RemoveByHost can't observe the
+	// appended item and drain this token in the same instant — tokens is
+	// buffered to capacity and len(items) was just checked against it, so
+	// this never blocks.
+	f.tokens <- struct{}{}
+	return nil
+}
+
+// Dequeue blocks until a job is available or ctx is canceled.
+func (f *FIFO) Dequeue(ctx context.Context) (*Job, bool) {
+	for {
+		select {
+		case <-f.tokens:
+			f.mu.Lock()
+			if len(f.items) == 0 {
+				// RemoveByHost won the race for the item this token
+				// represented (it ran between our token take and us
+				// grabbing mu) — nothing to pop, wait for the next one.
+				f.mu.Unlock()
+				continue
+			}
+			job := f.items[0]
+			f.items = f.items[1:]
+			f.mu.Unlock()
+			return job, true
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+// RemoveByHost drops every queued job whose target host matches host,
+// without waking a Dequeue for them, and returns how many were removed.
+func (f *FIFO) RemoveByHost(host string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	kept := f.items[:0]
+	removed := 0
+	for _, job := range f.items {
+		if hostOf(job.Target) == host {
+			removed++
+			select {
+			case <-f.tokens:
+			default:
+			}
+			continue
+		}
+		kept = append(kept, job)
+	}
+	f.items = kept
+	return removed
+}
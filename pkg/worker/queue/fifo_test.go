@@ -0,0 +1,38 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestFIFOEnqueueRemoveByHostRace reproduces Enqueue racing RemoveByHost for
+// the same job: if the token send isn't atomic with the append, a purge can
+// remove the item without draining its token, leaving an orphan token that
+// later makes Dequeue panic on an empty slice.
+func TestFIFOEnqueueRemoveByHostRace(t *testing.T) {
+	f := NewFIFO(1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		job := newJob("job", "http://victim.example/hook", nil)
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = f.Enqueue(job)
+		}()
+		go func() {
+			defer wg.Done()
+			f.RemoveByHost("victim.example")
+		}()
+		wg.Wait()
+
+		// Drain whatever is left so the next iteration starts from empty,
+		// without ever blocking — a panic here means items/tokens desynced.
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		f.Dequeue(ctx)
+		cancel()
+	}
+}
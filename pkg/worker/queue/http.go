@@ -0,0 +1,78 @@
+package queue
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// maxJobBodyBytes bounds a POST /jobs request body, so an arbitrarily large
+// payload can't be used to exhaust worker memory.
+const maxJobBodyBytes = 1 << 20 // 1MiB
+
+type enqueueRequest struct {
+	Target  string          `json:"target"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// EnqueueHandler serves POST /jobs — enqueue an arbitrary JSON payload for
+// delivery to a target URL.
+func (m *Manager) EnqueueHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxJobBodyBytes)
+
+		var req enqueueRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Target == "" {
+			http.Error(w, `missing or invalid "target"`, http.StatusBadRequest)
+			return
+		}
+
+		job, err := m.Enqueue(req.Target, req.Payload)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(job.View())
+	}
+}
+
+// StatusHandler serves GET /jobs/{id} — the job's current status and
+// attempt count.
+func (m *Manager) StatusHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/jobs/")
+		if id == "" || strings.Contains(id, "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		job, ok := m.Get(id)
+		if !ok {
+			http.Error(w, "job not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(job.View())
+	}
+}
+
+// PurgeHandler serves DELETE /jobs?target=host — drops every still-queued
+// job bound for that host, for use during incident response.
+func (m *Manager) PurgeHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		target := r.URL.Query().Get("target")
+		if target == "" {
+			http.Error(w, `missing "target" query param`, http.StatusBadRequest)
+			return
+		}
+
+		removed := m.Purge(target)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{"removed": removed})
+	}
+}
@@ -0,0 +1,33 @@
+package queue
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestEnqueueHandlerRejectsOversizedBody ensures POST /jobs can't be used to
+// exhaust worker memory with an arbitrarily large request body.
+func TestEnqueueHandlerRejectsOversizedBody(t *testing.T) {
+	mgr := NewManager(DefaultConfig())
+
+	payload, err := json.Marshal(map[string]string{
+		"target":  "http://example.com/hook",
+		"payload": strings.Repeat("a", maxJobBodyBytes+1),
+	})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/jobs", bytes.NewReader(payload))
+	rr := httptest.NewRecorder()
+
+	mgr.EnqueueHandler()(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d for an oversized body", rr.Code, http.StatusBadRequest)
+	}
+}
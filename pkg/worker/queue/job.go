@@ -0,0 +1,134 @@
+package queue
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Status is a Job's delivery state.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusInFlight  Status = "in_flight"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// Job is a single delivery task: an arbitrary JSON payload bound for a
+// target URL, with the delivery state tracked as it's retried.
+type Job struct {
+	ID      string
+	Target  string
+	Payload json.RawMessage
+
+	mu         sync.Mutex
+	status     Status
+	attempts   int
+	lastErr    string
+	finishedAt time.Time
+}
+
+// isTerminal reports whether s is a final delivery state the job will never
+// leave.
+func isTerminal(s Status) bool {
+	return s == StatusSucceeded || s == StatusFailed
+}
+
+func newJob(id, target string, payload json.RawMessage) *Job {
+	return &Job{ID: id, Target: target, Payload: payload, status: StatusPending}
+}
+
+func (j *Job) setStatus(s Status) {
+	j.mu.Lock()
+	j.status = s
+	if isTerminal(s) {
+		j.finishedAt = time.Now()
+	}
+	j.mu.Unlock()
+}
+
+// Status returns the job's current delivery state.
+func (j *Job) Status() Status {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+func (j *Job) recordAttempt() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.attempts++
+	return j.attempts
+}
+
+// Attempts returns how many delivery attempts have been made so far.
+func (j *Job) Attempts() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.attempts
+}
+
+func (j *Job) setLastErr(err error) {
+	j.mu.Lock()
+	if err != nil {
+		j.lastErr = err.Error()
+	}
+	j.mu.Unlock()
+}
+
+// failIfPending marks the job StatusFailed if it is currently StatusPending
+// (i.e. queued or backing off, not already in flight, failed, or
+// succeeded), returning whether it did.
+func (j *Job) failIfPending(err error) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status != StatusPending {
+		return false
+	}
+	j.status = StatusFailed
+	j.finishedAt = time.Now()
+	if err != nil {
+		j.lastErr = err.Error()
+	}
+	return true
+}
+
+// finishedBefore reports whether the job reached a terminal state before t.
+// Always false for a job still in progress.
+func (j *Job) finishedBefore(t time.Time) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return isTerminal(j.status) && j.finishedAt.Before(t)
+}
+
+// requeueUnlessFailed calls enqueue unless the job has already been marked
+// StatusFailed (e.g. by Purge) since its retry was scheduled. The check and
+// the enqueue happen under the job's own lock so the two can't race with a
+// concurrent failIfPending.
+func (j *Job) requeueUnlessFailed(enqueue func() error) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status == StatusFailed {
+		return nil
+	}
+	return enqueue()
+}
+
+// View is a JSON-safe snapshot of a Job's state, as returned by
+// GET /jobs/{id}.
+type View struct {
+	ID       string `json:"id"`
+	Target   string `json:"target"`
+	Status   Status `json:"status"`
+	Attempts int    `json:"attempts"`
+	LastErr  string `json:"last_error,omitempty"`
+}
+
+// View returns a point-in-time snapshot of the job's state.
+func (j *Job) View() View {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return View{ID: j.ID, Target: j.Target, Status: j.status, Attempts: j.attempts, LastErr: j.lastErr}
+}
@@ -0,0 +1,282 @@
+// Package queue implements a bounded in-memory job queue with a pool of
+// delivery workers, per-job exponential backoff with jitter, and a per-host
+// circuit breaker — the real delivery subsystem behind the worker service's
+// former "processing batch N" placeholder loop.
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config tunes worker pool size, retry backoff, and circuit-breaker
+// behavior. All fields are required; see DefaultConfig for reasonable
+// defaults.
+type Config struct {
+	Workers     int
+	Capacity    int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	MaxAttempts int
+
+	BreakerThreshold int           // consecutive failures against a host before it trips
+	BreakerCooldown  time.Duration // how long a tripped breaker pauses delivery to that host
+
+	// JobRetention controls how long a job stays queryable via
+	// GET /jobs/{id} after reaching a terminal state (succeeded or
+	// permanently failed) before it's evicted from memory. Without this,
+	// Manager would keep every job it has ever seen for the life of the
+	// process, letting an unbounded stream of POST /jobs calls OOM it.
+	JobRetention time.Duration
+
+	// OnEvent, if set, is called with a short human-readable description of
+	// every delivery outcome (success, failure, permanent failure, breaker
+	// trip). Used to feed the live event stream; safe to leave nil.
+	OnEvent func(kind, message string)
+}
+
+// jobSweepInterval is how often Manager checks for terminal jobs older than
+// JobRetention to evict.
+const jobSweepInterval = time.Minute
+
+// DefaultConfig returns the queue's out-of-the-box tuning.
+func DefaultConfig() Config {
+	return Config{
+		Workers:          4,
+		Capacity:         1000,
+		BaseDelay:        500 * time.Millisecond,
+		MaxDelay:         30 * time.Second,
+		MaxAttempts:      5,
+		BreakerThreshold: 3,
+		BreakerCooldown:  30 * time.Second,
+		JobRetention:     10 * time.Minute,
+	}
+}
+
+// Manager owns the job queue, the delivery worker pool, and per-host
+// circuit breakers.
+type Manager struct {
+	cfg    Config
+	fifo   *FIFO
+	client *http.Client
+	nextID atomic.Int64
+
+	jobsMu sync.RWMutex
+	jobs   map[string]*Job
+
+	breakersMu sync.Mutex
+	breakers   map[string]*breaker
+}
+
+// NewManager builds a Manager with the given Config. Call Start to run its
+// delivery workers.
+func NewManager(cfg Config) *Manager {
+	return &Manager{
+		cfg:      cfg,
+		fifo:     NewFIFO(cfg.Capacity),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		jobs:     make(map[string]*Job),
+		breakers: make(map[string]*breaker),
+	}
+}
+
+// emit reports a delivery event via cfg.OnEvent, if set.
+func (m *Manager) emit(kind, message string) {
+	if m.cfg.OnEvent != nil {
+		m.cfg.OnEvent(kind, message)
+	}
+}
+
+// Start launches cfg.Workers delivery workers and the job-retention
+// janitor. They run until ctx is canceled.
+func (m *Manager) Start(ctx context.Context) {
+	for i := 0; i < m.cfg.Workers; i++ {
+		go m.worker(ctx)
+	}
+	go m.sweepJobs(ctx)
+}
+
+// sweepJobs periodically evicts jobs that reached a terminal state more
+// than cfg.JobRetention ago, so GET /jobs/{id} history doesn't grow without
+// bound for the life of the process.
+func (m *Manager) sweepJobs(ctx context.Context) {
+	ticker := time.NewTicker(jobSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.evictFinishedBefore(time.Now().Add(-m.cfg.JobRetention))
+		}
+	}
+}
+
+func (m *Manager) evictFinishedBefore(cutoff time.Time) {
+	m.jobsMu.Lock()
+	defer m.jobsMu.Unlock()
+	for id, job := range m.jobs {
+		if job.finishedBefore(cutoff) {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+func (m *Manager) worker(ctx context.Context) {
+	for {
+		job, ok := m.fifo.Dequeue(ctx)
+		if !ok {
+			return
+		}
+		m.deliver(ctx, job)
+	}
+}
+
+// Enqueue creates a new job for target with the given payload and adds it
+// to the delivery queue.
+func (m *Manager) Enqueue(target string, payload json.RawMessage) (*Job, error) {
+	id := fmt.Sprintf("job-%d", m.nextID.Add(1))
+	job := newJob(id, target, payload)
+
+	if err := m.fifo.Enqueue(job); err != nil {
+		return nil, err
+	}
+
+	m.jobsMu.Lock()
+	m.jobs[id] = job
+	m.jobsMu.Unlock()
+
+	return job, nil
+}
+
+// Get returns the job with the given ID, if any.
+func (m *Manager) Get(id string) (*Job, bool) {
+	m.jobsMu.RLock()
+	defer m.jobsMu.RUnlock()
+	job, ok := m.jobs[id]
+	return job, ok
+}
+
+// Purge removes every still-queued job targeting host from the FIFO and
+// marks them failed, returning how many were purged.
+func (m *Manager) Purge(host string) int {
+	removed := m.fifo.RemoveByHost(host)
+
+	m.jobsMu.RLock()
+	defer m.jobsMu.RUnlock()
+	for _, job := range m.jobs {
+		if hostOf(job.Target) == host {
+			job.failIfPending(errors.New("purged: target host removed from queue"))
+		}
+	}
+	return removed
+}
+
+func (m *Manager) deliver(ctx context.Context, job *Job) {
+	host := hostOf(job.Target)
+
+	if b := m.breakerFor(host); b.open() {
+		// Breaker's still open — don't burn an attempt, just requeue once
+		// the cooldown has a chance to have passed.
+		time.AfterFunc(m.cfg.BreakerCooldown, func() {
+			// requeueUnlessFailed no-ops if Purge marked this job failed
+			// while it was waiting out the breaker cooldown, atomically
+			// with that check so the two can't race.
+			err := job.requeueUnlessFailed(func() error { return m.fifo.Enqueue(job) })
+			if err != nil {
+				job.setStatus(StatusFailed)
+				job.setLastErr(err)
+			}
+		})
+		return
+	}
+
+	job.setStatus(StatusInFlight)
+	attempt := job.recordAttempt()
+
+	if err := m.send(ctx, job); err != nil {
+		job.setLastErr(err)
+		b := m.breakerFor(host)
+		if b.recordFailure(m.cfg.BreakerThreshold, m.cfg.BreakerCooldown) {
+			log.Printf("queue: circuit breaker open for host %s for %s", host, m.cfg.BreakerCooldown)
+			m.emit("breaker_open", fmt.Sprintf("circuit breaker open for host %s for %s", host, m.cfg.BreakerCooldown))
+		}
+
+		if attempt >= m.cfg.MaxAttempts {
+			job.setStatus(StatusFailed)
+			log.Printf("queue: job %s to %s failed permanently after %d attempts: %v", job.ID, job.Target, attempt, err)
+			m.emit("job_failed", fmt.Sprintf("job %s to %s failed permanently after %d attempts: %v", job.ID, job.Target, attempt, err))
+			return
+		}
+
+		job.setStatus(StatusPending)
+		delay := backoff(m.cfg.BaseDelay, m.cfg.MaxDelay, attempt)
+		time.AfterFunc(delay, func() {
+			// requeueUnlessFailed no-ops if Purge marked this job failed
+			// while it was in backoff, atomically with that check so the
+			// two can't race.
+			err := job.requeueUnlessFailed(func() error { return m.fifo.Enqueue(job) })
+			if err != nil {
+				job.setStatus(StatusFailed)
+				job.setLastErr(err)
+			}
+		})
+		return
+	}
+
+	m.breakerFor(host).recordSuccess()
+	job.setStatus(StatusSucceeded)
+	m.emit("job_succeeded", fmt.Sprintf("job %s delivered to %s", job.ID, job.Target))
+}
+
+func (m *Manager) send(ctx context.Context, job *Job) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.Target, bytes.NewReader(job.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("delivery to %s failed with status %d", job.Target, resp.StatusCode)
+	}
+	return nil
+}
+
+func (m *Manager) breakerFor(host string) *breaker {
+	m.breakersMu.Lock()
+	defer m.breakersMu.Unlock()
+
+	b, ok := m.breakers[host]
+	if !ok {
+		b = &breaker{}
+		m.breakers[host] = b
+	}
+	return b
+}
+
+// backoff computes an exponential delay for the given attempt number
+// (1-indexed), capped at max, with up to 50% jitter.
+func backoff(base, max time.Duration, attempt int) time.Duration {
+	d := base << uint(attempt-1)
+	if d <= 0 || d > max {
+		d = max
+	}
+	jitter := time.Duration(rand.Int63n(int64(d)/2 + 1))
+	return d/2 + jitter
+}
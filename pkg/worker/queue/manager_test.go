@@ -0,0 +1,94 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestPurgeCancelsPendingBackoff reproduces a job that's purged while
+// waiting out its retry backoff: Purge marks it StatusFailed, and the
+// backoff timer firing afterward must not resurrect it against the purged
+// host.
+func TestPurgeCancelsPendingBackoff(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.Workers = 1
+	// Generous relative to the poll/purge round-trip below so a loaded
+	// machine (e.g. running under -race alongside other tests) can't cause
+	// the backoff timer to fire before Purge runs and still pass.
+	cfg.BaseDelay = 2 * time.Second
+	cfg.MaxDelay = 2 * time.Second
+	cfg.MaxAttempts = 5
+
+	mgr := NewManager(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mgr.Start(ctx)
+
+	job, err := mgr.Enqueue(srv.URL+"/hook", json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	// Wait for the first delivery attempt to fail and drop the job into
+	// backoff (StatusPending) before its retry timer fires.
+	deadline := time.Now().Add(time.Second)
+	for job.Status() != StatusPending && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if job.Status() != StatusPending {
+		t.Fatalf("job never entered backoff, status = %s", job.Status())
+	}
+
+	mgr.Purge(hostOf(job.Target))
+	if job.Status() != StatusFailed {
+		t.Fatalf("Purge did not mark the backing-off job failed, status = %s", job.Status())
+	}
+
+	// Give the backoff timer time to fire and confirm it didn't resurrect
+	// the job against the purged host.
+	time.Sleep(3 * time.Second)
+	if job.Status() != StatusFailed {
+		t.Fatalf("backoff timer resurrected a purged job, status = %s", job.Status())
+	}
+}
+
+// TestEvictFinishedBeforeDropsOnlyOldTerminalJobs verifies the retention
+// sweep only removes jobs that are both terminal and older than the cutoff,
+// leaving in-progress and recently-finished jobs alone.
+func TestEvictFinishedBeforeDropsOnlyOldTerminalJobs(t *testing.T) {
+	mgr := NewManager(DefaultConfig())
+
+	old := newJob("old", "http://example.com/hook", nil)
+	old.setStatus(StatusFailed)
+	old.finishedAt = time.Now().Add(-time.Hour)
+
+	recent := newJob("recent", "http://example.com/hook", nil)
+	recent.setStatus(StatusSucceeded)
+
+	pending := newJob("pending", "http://example.com/hook", nil)
+
+	mgr.jobs[old.ID] = old
+	mgr.jobs[recent.ID] = recent
+	mgr.jobs[pending.ID] = pending
+
+	mgr.evictFinishedBefore(time.Now().Add(-time.Minute))
+
+	if _, ok := mgr.Get(old.ID); ok {
+		t.Error("evictFinishedBefore did not evict a job finished well before the cutoff")
+	}
+	if _, ok := mgr.Get(recent.ID); !ok {
+		t.Error("evictFinishedBefore evicted a job finished after the cutoff")
+	}
+	if _, ok := mgr.Get(pending.ID); !ok {
+		t.Error("evictFinishedBefore evicted a job that's still in progress")
+	}
+}
@@ -1,66 +1,84 @@
-package main
+package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"math/rand"
 	"net/http"
 	"os"
-	"os/signal"
 	"sort"
+	"strconv"
 	"strings"
 	"sync/atomic"
-	"syscall"
 	"time"
 
 	"github.com/unkeyed/mono-repo-test/pkg/shared"
+	"github.com/unkeyed/mono-repo-test/pkg/shared/clientip"
+	"github.com/unkeyed/mono-repo-test/pkg/shared/events"
+	"github.com/unkeyed/mono-repo-test/pkg/shared/health"
+	"github.com/unkeyed/mono-repo-test/pkg/shared/lifecycle"
+	"github.com/unkeyed/mono-repo-test/pkg/shared/nettest"
 )
 
-func main() {
+func Run() {
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "3456"
 	}
 
-	// Simulate startup delay — healthcheck should fail during this window
-	var ready atomic.Bool
+	// Simulate startup delay — readiness should fail during this window
+	var warmedUp atomic.Bool
 	startupDelay := 3 * time.Second
 	log.Printf("api: starting up, will be ready in %s", startupDelay)
 	go func() {
 		time.Sleep(startupDelay)
-		ready.Store(true)
+		warmedUp.Store(true)
 		log.Println("api: ready to serve traffic")
 	}()
 
-	// Toggle health on/off via POST /healthz/fail and POST /healthz/recover
+	readiness := health.NewRegistry()
+	readiness.Register("warmup", func(ctx context.Context) error {
+		if !warmedUp.Load() {
+			return fmt.Errorf("still starting up")
+		}
+		return nil
+	})
+
+	// All-pairs connectivity mesh — every instance writes to every peer in
+	// PEERS (or PEERS_FILE) on a schedule, so /nettest/status reports whether
+	// the mesh has converged under the current network policy.
+	// self must be the exact string other instances' PEERS entries use to
+	// reach us (e.g. "http://api:3456"), since StatusHandler matches heard
+	// senders against its own PEERS list verbatim.
+	self := os.Getenv("SELF_URL")
+	if self == "" {
+		self = "http://api:" + port
+	}
+	peers, err := nettest.LoadPeers()
+	if err != nil {
+		log.Printf("api: nettest: %v", err)
+	}
+	mesh := nettest.NewMesh(self, peers, envDuration("NETTEST_INTERVAL_SECONDS", 5*time.Second), envDuration("NETTEST_TIMEOUT_SECONDS", 30*time.Second))
+	if len(peers) > 0 {
+		go mesh.Run(context.Background())
+	}
+
+	// Toggle liveness on/off via POST /livez/fail and POST /livez/recover
 	var forceFail atomic.Bool
 
-	// Track in-flight requests for graceful shutdown
+	// Track in-flight requests for the "/" handler's status message
 	var inflight atomic.Int64
 
-	// Handle shutdown signals — log exactly which signal we got
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGKILL)
-	go func() {
-		s := <-sig
-		log.Printf("api: received %s — starting graceful shutdown", s)
-
-		// Wait for in-flight requests to drain
-		deadline := time.After(10 * time.Second)
-		for inflight.Load() > 0 {
-			select {
-			case <-deadline:
-				log.Printf("api: shutdown deadline reached with %d in-flight requests", inflight.Load())
-				os.Exit(1)
-			default:
-				time.Sleep(100 * time.Millisecond)
-			}
-		}
+	trustedProxies, err := clientip.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Printf("api: %v — proceeding with no trusted proxies", err)
+	}
 
-		log.Printf("api: clean shutdown after %s", s)
-		os.Exit(0)
-	}()
+	// Live event stream — GET /events (WebSocket) tails requests,
+	// healthcheck toggles, probes, and signals as they happen.
+	broker := events.NewBroker(64)
 
 	mux := http.NewServeMux()
 
@@ -68,64 +86,63 @@ func main() {
 		inflight.Add(1)
 		defer inflight.Add(-1)
 
-		shared.JSON(w, http.StatusOK, shared.Response{
+		n := rand.Intn(10000)
+		broker.Publish(events.Event{Service: "api", Kind: "request", Message: fmt.Sprintf("request #%d | in-flight: %d", n, inflight.Load()), Timestamp: time.Now()})
+		shared.JSON(w, r, http.StatusOK, shared.Response{
 			Service: "api",
 			Status:  "ok",
 			Port:    port,
-			Message: fmt.Sprintf("request #%d | in-flight: %d", rand.Intn(10000), inflight.Load()),
+			Message: fmt.Sprintf("request #%d | in-flight: %d", n, inflight.Load()),
 		})
 	})
 
-	// Healthcheck endpoint — fails during startup and when toggled
-	healthzHandler := func(w http.ResponseWriter, r *http.Request) {
-		if !ready.Load() {
-			shared.JSON(w, http.StatusServiceUnavailable, shared.Response{
-				Service: "api",
-				Status:  "not_ready",
-				Port:    port,
-				Message: "still starting up",
-			})
-			return
-		}
+	// GET /livez — process-alive check, only fails when manually toggled via
+	// POST /livez/fail. Never reflects dependency state; that's /readyz.
+	mux.HandleFunc("GET /livez", func(w http.ResponseWriter, r *http.Request) {
 		if forceFail.Load() {
-			shared.JSON(w, http.StatusServiceUnavailable, shared.Response{
+			shared.JSON(w, r, http.StatusServiceUnavailable, shared.Response{
 				Service: "api",
 				Status:  "unhealthy",
 				Port:    port,
-				Message: "health manually toggled to fail",
+				Message: "liveness manually toggled to fail",
 			})
 			return
 		}
-		shared.JSON(w, http.StatusOK, shared.Response{
+		shared.JSON(w, r, http.StatusOK, shared.Response{
 			Service: "api",
 			Status:  "healthy",
 			Port:    port,
 		})
-	}
-	mux.HandleFunc("GET /healthz", healthzHandler)
-	mux.HandleFunc("POST /healthz", healthzHandler)
+	})
 
-	// POST /healthz/fail — make healthcheck start failing (triggers liveness probe restart)
-	mux.HandleFunc("POST /healthz/fail", func(w http.ResponseWriter, r *http.Request) {
+	// GET /readyz — 200 once every registered readiness check passes, 503
+	// otherwise. ?verbose=1 for a per-check breakdown, ?exclude=a,b to skip
+	// specific checks (e.g. during incident response).
+	mux.HandleFunc("GET /readyz", readiness.ReadyzHandler("api", port))
+
+	// POST /livez/fail — make liveness start failing (triggers restart)
+	mux.HandleFunc("POST /livez/fail", func(w http.ResponseWriter, r *http.Request) {
 		forceFail.Store(true)
-		log.Println("api: healthcheck toggled to FAIL")
-		shared.JSON(w, http.StatusOK, shared.Response{
+		log.Println("api: liveness toggled to FAIL")
+		broker.Publish(events.Event{Service: "api", Kind: "livez", Message: "liveness toggled to fail", Timestamp: time.Now()})
+		shared.JSON(w, r, http.StatusOK, shared.Response{
 			Service: "api",
 			Status:  "ok",
 			Port:    port,
-			Message: "healthcheck will now fail — liveness probe should restart this container",
+			Message: "liveness will now fail — liveness probe should restart this container",
 		})
 	})
 
-	// POST /healthz/recover — make healthcheck pass again
-	mux.HandleFunc("POST /healthz/recover", func(w http.ResponseWriter, r *http.Request) {
+	// POST /livez/recover — make liveness pass again
+	mux.HandleFunc("POST /livez/recover", func(w http.ResponseWriter, r *http.Request) {
 		forceFail.Store(false)
-		log.Println("api: healthcheck toggled to PASS")
-		shared.JSON(w, http.StatusOK, shared.Response{
+		log.Println("api: liveness toggled to PASS")
+		broker.Publish(events.Event{Service: "api", Kind: "livez", Message: "liveness toggled to pass", Timestamp: time.Now()})
+		shared.JSON(w, r, http.StatusOK, shared.Response{
 			Service: "api",
 			Status:  "ok",
 			Port:    port,
-			Message: "healthcheck will now pass again",
+			Message: "liveness will now pass again",
 		})
 	})
 
@@ -137,7 +154,7 @@ func main() {
 		duration := 5 * time.Second
 		log.Printf("api: slow request started, will take %s", duration)
 		time.Sleep(duration)
-		shared.JSON(w, http.StatusOK, shared.Response{
+		shared.JSON(w, r, http.StatusOK, shared.Response{
 			Service: "api",
 			Status:  "ok",
 			Port:    port,
@@ -153,7 +170,7 @@ func main() {
 		principal := r.Header.Get("X-Unkey-Principal")
 		if principal == "" {
 			log.Println("api: GET /protected — no X-Unkey-Principal header")
-			shared.JSON(w, http.StatusUnauthorized, shared.Response{
+			shared.JSON(w, r, http.StatusUnauthorized, shared.Response{
 				Service: "api",
 				Status:  "unauthorized",
 				Port:    port,
@@ -168,7 +185,7 @@ func main() {
 		var parsed map[string]any
 		if err := json.Unmarshal([]byte(principal), &parsed); err != nil {
 			log.Printf("api: GET /protected — failed to parse principal JSON: %v", err)
-			shared.JSON(w, http.StatusOK, shared.Response{
+			shared.JSON(w, r, http.StatusOK, shared.Response{
 				Service: "api",
 				Status:  "ok",
 				Port:    port,
@@ -212,7 +229,7 @@ func main() {
 	mux.HandleFunc("GET /probe", func(w http.ResponseWriter, r *http.Request) {
 		workerURL := os.Getenv("WORKER_URL")
 		if workerURL == "" {
-			shared.JSON(w, http.StatusOK, shared.Response{
+			shared.JSON(w, r, http.StatusOK, shared.Response{
 				Service: "api",
 				Status:  "skipped",
 				Port:    port,
@@ -223,10 +240,11 @@ func main() {
 
 		log.Printf("api: probing worker at %s", workerURL)
 		client := &http.Client{Timeout: 3 * time.Second}
-		resp, err := client.Get(workerURL + "/healthz")
+		resp, err := client.Get(workerURL + "/readyz")
 		if err != nil {
 			log.Printf("api: probe FAILED (network isolation working): %v", err)
-			shared.JSON(w, http.StatusOK, shared.Response{
+			broker.Publish(events.Event{Service: "api", Kind: "probe", Message: fmt.Sprintf("cannot reach worker at %s: %v", workerURL, err), Timestamp: time.Now()})
+			shared.JSON(w, r, http.StatusOK, shared.Response{
 				Service: "api",
 				Status:  "isolated",
 				Port:    port,
@@ -237,7 +255,8 @@ func main() {
 		defer resp.Body.Close()
 
 		log.Printf("api: probe SUCCEEDED (network isolation BROKEN): status %d", resp.StatusCode)
-		shared.JSON(w, http.StatusOK, shared.Response{
+		broker.Publish(events.Event{Service: "api", Kind: "probe", Message: fmt.Sprintf("reached worker at %s — got HTTP %d", workerURL, resp.StatusCode), Timestamp: time.Now()})
+		shared.JSON(w, r, http.StatusOK, shared.Response{
 			Service: "api",
 			Status:  "NOT_ISOLATED",
 			Port:    port,
@@ -245,8 +264,39 @@ func main() {
 		})
 	})
 
+	// Connectivity mesh — POST /nettest/write is hit by peers on their
+	// schedule, GET /nettest/read shows what we've heard, GET /nettest/status
+	// summarizes convergence for CI gating.
+	mux.HandleFunc("POST /nettest/write", mesh.WriteHandler())
+	mux.HandleFunc("GET /nettest/read", mesh.ReadHandler())
+	mux.HandleFunc("GET /nettest/status", mesh.StatusHandler())
+
+	// GET /events — live WebSocket tail of requests, healthcheck toggles,
+	// probes, and shutdown signals. ?filter=service=api,kind=probe narrows it.
+	mux.HandleFunc("GET /events", broker.Handler("api"))
+
+	srv := &http.Server{Addr: ":" + port, Handler: clientip.LoggingMiddleware("api", trustedProxies)(mux)}
+	grace := envDuration("SHUTDOWN_GRACE_SECONDS", 10*time.Second)
+	shutdownNotice := lifecycle.Pre("publish-shutdown-event", func(context.Context) {
+		broker.Publish(events.Event{Service: "api", Kind: "shutdown", Message: "shutdown signal received, draining connections", Timestamp: time.Now()})
+	})
+
 	log.Printf("api: listening on :%s", port)
-	if err := http.ListenAndServe(":"+port, mux); err != nil {
+	if err := lifecycle.Run(context.Background(), srv, grace, shutdownNotice); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// envDuration reads an integer number of seconds from the named env var,
+// falling back to def if unset or invalid.
+func envDuration(name string, def time.Duration) time.Duration {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	seconds, err := strconv.Atoi(raw)
+	if err != nil {
+		return def
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -1,16 +1,22 @@
 package worker
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"os/signal"
+	"strconv"
 	"sync/atomic"
-	"syscall"
 	"time"
 
 	"github.com/unkeyed/mono-repo-test/pkg/shared"
+	"github.com/unkeyed/mono-repo-test/pkg/shared/clientip"
+	"github.com/unkeyed/mono-repo-test/pkg/shared/events"
+	"github.com/unkeyed/mono-repo-test/pkg/shared/health"
+	"github.com/unkeyed/mono-repo-test/pkg/shared/lifecycle"
+	"github.com/unkeyed/mono-repo-test/pkg/shared/nettest"
+	"github.com/unkeyed/mono-repo-test/pkg/worker/queue"
 )
 
 func Run() {
@@ -19,92 +25,128 @@ func Run() {
 		port = "9090"
 	}
 
-	var ready atomic.Bool
+	var warmedUp atomic.Bool
 	var forceFail atomic.Bool
 
 	// Simulate startup: worker needs to "warm up" before it's ready
 	log.Println("worker: warming up...")
 	go func() {
 		time.Sleep(2 * time.Second)
-		ready.Store(true)
+		warmedUp.Store(true)
 		log.Println("worker: warm-up complete, ready")
 	}()
 
-	// Handle shutdown signals — log which signal and do cleanup
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGKILL)
-	go func() {
-		s := <-sig
-		log.Printf("worker: received %s", s)
-		log.Println("worker: flushing pending work...")
-		time.Sleep(2 * time.Second) // simulate flush
-		log.Printf("worker: clean shutdown after %s", s)
-		os.Exit(0)
-	}()
-
-	// Background work loop
-	go func() {
-		tick := time.NewTicker(5 * time.Second)
-		defer tick.Stop()
-		batch := 0
-		for range tick.C {
-			batch++
-			log.Printf("worker: processing batch %d...", batch)
+	readiness := health.NewRegistry()
+	readiness.Register("warmup", func(ctx context.Context) error {
+		if !warmedUp.Load() {
+			return fmt.Errorf("still warming up")
 		}
-	}()
+		return nil
+	})
+
+	// ctx is canceled once shutdown begins, stopping the nettest mesh loop
+	// and the job delivery workers.
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// All-pairs connectivity mesh — every instance writes to every peer in
+	// PEERS (or PEERS_FILE) on a schedule, so /nettest/status reports whether
+	// the mesh has converged under the current network policy.
+	// self must be the exact string other instances' PEERS entries use to
+	// reach us (e.g. "http://worker:9090"), since StatusHandler matches heard
+	// senders against its own PEERS list verbatim.
+	self := os.Getenv("SELF_URL")
+	if self == "" {
+		self = "http://worker:" + port
+	}
+	peers, err := nettest.LoadPeers()
+	if err != nil {
+		log.Printf("worker: nettest: %v", err)
+	}
+	mesh := nettest.NewMesh(self, peers, envDuration("NETTEST_INTERVAL_SECONDS", 5*time.Second), envDuration("NETTEST_TIMEOUT_SECONDS", 30*time.Second))
+	if len(peers) > 0 {
+		go mesh.Run(ctx)
+	}
+
+	// Live event stream — GET /events (WebSocket) tails requests,
+	// healthcheck toggles, probes, job deliveries, and signals as they
+	// happen.
+	broker := events.NewBroker(64)
+
+	// Job delivery queue — replaces the old placeholder "processing batch N"
+	// ticker with a real bounded queue, a pool of delivery workers, and
+	// per-host backoff and circuit breaking.
+	jobsCfg := queue.DefaultConfig()
+	jobsCfg.Workers = envInt("QUEUE_WORKERS", jobsCfg.Workers)
+	jobsCfg.Capacity = envInt("QUEUE_CAPACITY", jobsCfg.Capacity)
+	jobsCfg.MaxAttempts = envInt("QUEUE_MAX_ATTEMPTS", jobsCfg.MaxAttempts)
+	jobsCfg.BreakerThreshold = envInt("QUEUE_BREAKER_THRESHOLD", jobsCfg.BreakerThreshold)
+	jobsCfg.BaseDelay = envMillis("QUEUE_BASE_DELAY_MS", jobsCfg.BaseDelay)
+	jobsCfg.MaxDelay = envMillis("QUEUE_MAX_DELAY_MS", jobsCfg.MaxDelay)
+	jobsCfg.BreakerCooldown = envDuration("QUEUE_BREAKER_COOLDOWN_SECONDS", jobsCfg.BreakerCooldown)
+	jobsCfg.JobRetention = envDuration("QUEUE_JOB_RETENTION_SECONDS", jobsCfg.JobRetention)
+	jobsCfg.OnEvent = func(kind, message string) {
+		broker.Publish(events.Event{Service: "worker", Kind: kind, Message: message, Timestamp: time.Now()})
+	}
+	jobs := queue.NewManager(jobsCfg)
+	jobs.Start(ctx)
+
+	trustedProxies, err := clientip.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Printf("worker: %v — proceeding with no trusted proxies", err)
+	}
 
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
-		if !ready.Load() {
-			shared.JSON(w, http.StatusServiceUnavailable, shared.Response{
-				Service: "worker",
-				Status:  "not_ready",
-				Port:    port,
-				Message: "still warming up",
-			})
-			return
-		}
+	// GET /livez — process-alive check, only fails when manually toggled via
+	// POST /livez/fail. Never reflects dependency state; that's /readyz.
+	mux.HandleFunc("GET /livez", func(w http.ResponseWriter, r *http.Request) {
 		if forceFail.Load() {
-			shared.JSON(w, http.StatusServiceUnavailable, shared.Response{
+			shared.JSON(w, r, http.StatusServiceUnavailable, shared.Response{
 				Service: "worker",
 				Status:  "unhealthy",
 				Port:    port,
-				Message: "health manually toggled to fail",
+				Message: "liveness manually toggled to fail",
 			})
 			return
 		}
-		shared.JSON(w, http.StatusOK, shared.Response{
+		shared.JSON(w, r, http.StatusOK, shared.Response{
 			Service: "worker",
 			Status:  "healthy",
 			Port:    port,
 		})
 	})
 
-	mux.HandleFunc("POST /healthz/fail", func(w http.ResponseWriter, r *http.Request) {
+	// GET /readyz — 200 once every registered readiness check passes, 503
+	// otherwise. ?verbose=1 for a per-check breakdown, ?exclude=a,b to skip
+	// specific checks (e.g. during incident response).
+	mux.HandleFunc("GET /readyz", readiness.ReadyzHandler("worker", port))
+
+	mux.HandleFunc("POST /livez/fail", func(w http.ResponseWriter, r *http.Request) {
 		forceFail.Store(true)
-		log.Println("worker: healthcheck toggled to FAIL")
-		shared.JSON(w, http.StatusOK, shared.Response{
+		log.Println("worker: liveness toggled to FAIL")
+		broker.Publish(events.Event{Service: "worker", Kind: "livez", Message: "liveness toggled to fail", Timestamp: time.Now()})
+		shared.JSON(w, r, http.StatusOK, shared.Response{
 			Service: "worker",
 			Status:  "ok",
 			Port:    port,
-			Message: "healthcheck will now fail",
+			Message: "liveness will now fail",
 		})
 	})
 
-	mux.HandleFunc("POST /healthz/recover", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("POST /livez/recover", func(w http.ResponseWriter, r *http.Request) {
 		forceFail.Store(false)
-		log.Println("worker: healthcheck toggled to PASS")
-		shared.JSON(w, http.StatusOK, shared.Response{
+		log.Println("worker: liveness toggled to PASS")
+		broker.Publish(events.Event{Service: "worker", Kind: "livez", Message: "liveness toggled to pass", Timestamp: time.Now()})
+		shared.JSON(w, r, http.StatusOK, shared.Response{
 			Service: "worker",
 			Status:  "ok",
 			Port:    port,
-			Message: "healthcheck will now pass",
+			Message: "liveness will now pass",
 		})
 	})
 
 	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
-		shared.JSON(w, http.StatusOK, shared.Response{
+		shared.JSON(w, r, http.StatusOK, shared.Response{
 			Service: "worker",
 			Status:  "ok",
 			Port:    port,
@@ -118,7 +160,7 @@ func Run() {
 	mux.HandleFunc("GET /probe", func(w http.ResponseWriter, r *http.Request) {
 		apiURL := os.Getenv("API_URL")
 		if apiURL == "" {
-			shared.JSON(w, http.StatusOK, shared.Response{
+			shared.JSON(w, r, http.StatusOK, shared.Response{
 				Service: "worker",
 				Status:  "skipped",
 				Port:    port,
@@ -129,10 +171,11 @@ func Run() {
 
 		log.Printf("worker: probing api at %s", apiURL)
 		client := &http.Client{Timeout: 3 * time.Second}
-		resp, err := client.Get(apiURL + "/healthz")
+		resp, err := client.Get(apiURL + "/readyz")
 		if err != nil {
 			log.Printf("worker: probe FAILED (network isolation working): %v", err)
-			shared.JSON(w, http.StatusOK, shared.Response{
+			broker.Publish(events.Event{Service: "worker", Kind: "probe", Message: fmt.Sprintf("cannot reach api at %s: %v", apiURL, err), Timestamp: time.Now()})
+			shared.JSON(w, r, http.StatusOK, shared.Response{
 				Service: "worker",
 				Status:  "isolated",
 				Port:    port,
@@ -143,7 +186,8 @@ func Run() {
 		defer resp.Body.Close()
 
 		log.Printf("worker: probe SUCCEEDED (network isolation BROKEN): status %d", resp.StatusCode)
-		shared.JSON(w, http.StatusOK, shared.Response{
+		broker.Publish(events.Event{Service: "worker", Kind: "probe", Message: fmt.Sprintf("reached api at %s — got HTTP %d", apiURL, resp.StatusCode), Timestamp: time.Now()})
+		shared.JSON(w, r, http.StatusOK, shared.Response{
 			Service: "worker",
 			Status:  "NOT_ISOLATED",
 			Port:    port,
@@ -151,8 +195,81 @@ func Run() {
 		})
 	})
 
+	// Connectivity mesh — POST /nettest/write is hit by peers on their
+	// schedule, GET /nettest/read shows what we've heard, GET /nettest/status
+	// summarizes convergence for CI gating.
+	mux.HandleFunc("POST /nettest/write", mesh.WriteHandler())
+	mux.HandleFunc("GET /nettest/read", mesh.ReadHandler())
+	mux.HandleFunc("GET /nettest/status", mesh.StatusHandler())
+
+	// Job delivery — POST /jobs to enqueue, GET /jobs/{id} for status,
+	// DELETE /jobs?target=host to purge queued jobs for a bad host.
+	mux.HandleFunc("POST /jobs", jobs.EnqueueHandler())
+	mux.HandleFunc("GET /jobs/", jobs.StatusHandler())
+	mux.HandleFunc("DELETE /jobs", jobs.PurgeHandler())
+
+	// GET /events — live WebSocket tail of requests, healthcheck toggles,
+	// probes, job deliveries, and shutdown signals. ?filter=kind=job_failed
+	// narrows it.
+	mux.HandleFunc("GET /events", broker.Handler("worker"))
+
+	srv := &http.Server{Addr: ":" + port, Handler: clientip.LoggingMiddleware("worker", trustedProxies)(mux)}
+	grace := envDuration("SHUTDOWN_GRACE_SECONDS", 10*time.Second)
+	shutdownNotice := lifecycle.Pre("publish-shutdown-event", func(context.Context) {
+		broker.Publish(events.Event{Service: "worker", Kind: "shutdown", Message: "shutdown signal received, draining connections", Timestamp: time.Now()})
+	})
+	flush := lifecycle.Pre("flush-pending-work", func(ctx context.Context) {
+		log.Println("worker: flushing pending work...")
+		time.Sleep(2 * time.Second) // simulate flush
+	})
+	stopBackground := lifecycle.Post("stop-background-work", func(context.Context) {
+		cancel()
+	})
+
 	log.Printf("worker: listening on :%s", port)
-	if err := http.ListenAndServe(":"+port, mux); err != nil {
+	if err := lifecycle.Run(ctx, srv, grace, shutdownNotice, flush, stopBackground); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// envDuration reads an integer number of seconds from the named env var,
+// falling back to def if unset or invalid.
+func envDuration(name string, def time.Duration) time.Duration {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	seconds, err := strconv.Atoi(raw)
+	if err != nil {
+		return def
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// envMillis reads an integer number of milliseconds from the named env var,
+// falling back to def if unset or invalid.
+func envMillis(name string, def time.Duration) time.Duration {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	millis, err := strconv.Atoi(raw)
+	if err != nil {
+		return def
+	}
+	return time.Duration(millis) * time.Millisecond
+}
+
+// envInt reads an integer from the named env var, falling back to def if
+// unset or invalid.
+func envInt(name string, def int) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		return def
+	}
+	return v
+}